@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: 0BSD
+
+package libstoragemgmt
+
+import (
+	"encoding/json"
+
+	errors "github.com/libstorage/libstoragemgmt-golang/errors"
+)
+
+// Client is used by applications to invoke methods against a running
+// plugin instance over the same wire protocol Plugin.Run serves.
+type Client struct {
+	tp transPort
+}
+
+// rpc sends method with args and decodes the result into out.  out may be
+// nil when the caller does not care about the response payload.
+func (c *Client) rpc(method string, args interface{}, out interface{}) error {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return &errors.LsmError{Code: errors.LibBug, Message: err.Error()}
+	}
+
+	resp, err := c.tp.rpc(method, raw)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp, out)
+}
+
+// VolumeGroups returns the volume groups known to the plugin, optionally
+// restricted to those matching search.
+func (c *Client) VolumeGroups(search ...string) ([]VolumeGroup, error) {
+	var result []VolumeGroup
+	err := c.rpc("volume_groups", struct {
+		Search []string `json:"search"`
+	}{search}, &result)
+	return result, err
+}
+
+// VolumeGroupCreate creates a new, empty volume group named name on system.
+func (c *Client) VolumeGroupCreate(name string, system *System) (*VolumeGroup, error) {
+	var result VolumeGroup
+	err := c.rpc("volume_group_create", struct {
+		Name   string  `json:"name"`
+		System *System `json:"system"`
+	}{name, system}, &result)
+	return &result, err
+}
+
+// VolumeGroupDelete deletes a volume group.  Member volumes are not
+// deleted.
+func (c *Client) VolumeGroupDelete(vg *VolumeGroup) error {
+	return c.rpc("volume_group_delete", struct {
+		VolumeGroup *VolumeGroup `json:"volume_group"`
+	}{vg}, nil)
+}
+
+// VolumeGroupAddVolume adds an existing volume to a volume group.
+func (c *Client) VolumeGroupAddVolume(vg *VolumeGroup, vol *Volume) error {
+	return c.rpc("volume_group_add_volume", struct {
+		VolumeGroup *VolumeGroup `json:"volume_group"`
+		Volume      *Volume      `json:"volume"`
+	}{vg, vol}, nil)
+}
+
+// VolumeGroupRemoveVolume removes a volume from a volume group without
+// deleting the volume itself.
+func (c *Client) VolumeGroupRemoveVolume(vg *VolumeGroup, vol *Volume) error {
+	return c.rpc("volume_group_remove_volume", struct {
+		VolumeGroup *VolumeGroup `json:"volume_group"`
+		Volume      *Volume      `json:"volume"`
+	}{vg, vol}, nil)
+}
+
+// VolumeGroupSnapshotCreate creates a crash-consistent snapshot of every
+// volume currently in group, returning the snapshot and the volumes it
+// covers.
+func (c *Client) VolumeGroupSnapshotCreate(group *VolumeGroup, name string) (*VolumeGroupSnapshot, []Volume, error) {
+	var result struct {
+		SnapShot VolumeGroupSnapshot `json:"snap_shot"`
+		Volumes  []Volume            `json:"volumes"`
+	}
+	err := c.rpc("volume_group_snap_shot_create", struct {
+		Group *VolumeGroup `json:"group"`
+		Name  string       `json:"name"`
+	}{group, name}, &result)
+	return &result.SnapShot, result.Volumes, err
+}
+
+// VolumeGroupSnapshotDelete deletes a volume group snapshot.
+func (c *Client) VolumeGroupSnapshotDelete(vg *VolumeGroup, snapShot *VolumeGroupSnapshot) error {
+	return c.rpc("volume_group_snap_shot_delete", struct {
+		VolumeGroup *VolumeGroup         `json:"volume_group"`
+		SnapShot    *VolumeGroupSnapshot `json:"snap_shot"`
+	}{vg, snapShot}, nil)
+}
+
+// VolumeGroupSnapshotRestore restores some or all of the volumes covered
+// by a volume group snapshot back to the point in time it was taken.
+func (c *Client) VolumeGroupSnapshotRestore(vg *VolumeGroup, snapShot *VolumeGroupSnapshot, volumes []Volume) error {
+	return c.rpc("volume_group_snap_shot_restore", struct {
+		VolumeGroup *VolumeGroup         `json:"volume_group"`
+		SnapShot    *VolumeGroupSnapshot `json:"snap_shot"`
+		Volumes     []Volume             `json:"volumes"`
+	}{vg, snapShot, volumes}, nil)
+}
+
+// VolumeGroupSnapshots returns the snapshots that exist for the specified
+// volume group.
+func (c *Client) VolumeGroupSnapshots(vg *VolumeGroup) ([]VolumeGroupSnapshot, error) {
+	var result []VolumeGroupSnapshot
+	err := c.rpc("volume_group_snap_shots", struct {
+		VolumeGroup *VolumeGroup `json:"volume_group"`
+	}{vg}, &result)
+	return result, err
+}
@@ -0,0 +1,825 @@
+// SPDX-License-Identifier: 0BSD
+
+package libstoragemgmt
+
+import (
+	"context"
+	"encoding/json"
+
+	errors "github.com/libstorage/libstoragemgmt-golang/errors"
+)
+
+// decodeParams unmarshals an RPC request's params into v, treating empty
+// params as a no-op so methods that take no arguments don't have to
+// special-case it.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &errors.LsmError{Code: errors.LibBug, Message: err.Error()}
+	}
+	return nil
+}
+
+// buildTable constructs the method name to handler dispatch table Run
+// consults for every request.  A callback left nil in cb is simply never
+// registered, so Run falls through to noSupport for it instead of calling
+// a nil function pointer.
+func buildTable(cb *PluginCallBacks) map[string]handler {
+	t := make(map[string]handler)
+
+	// ManagementOps
+
+	if cb.Mgmt.TimeOutSet != nil {
+		t["time_out_set"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Timeout uint32 `json:"timeout"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.Mgmt.TimeOutSet(ctx, args.Timeout)
+		}
+	}
+
+	if cb.Mgmt.TimeOutGet != nil {
+		t["time_out_get"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			return cb.Mgmt.TimeOutGet(ctx), nil
+		}
+	}
+
+	if cb.Mgmt.JobStatus != nil {
+		t["job_status"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				JobID string `json:"job_id"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.Mgmt.JobStatus(ctx, args.JobID)
+		}
+	}
+
+	if cb.Mgmt.JobFree != nil {
+		t["job_free"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				JobID string `json:"job_id"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.Mgmt.JobFree(ctx, args.JobID)
+		}
+	}
+
+	if cb.Mgmt.Capabilities != nil {
+		t["capabilities"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				System *System `json:"system"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.Mgmt.Capabilities(ctx, args.System)
+		}
+	}
+
+	if cb.Mgmt.Systems != nil {
+		t["systems"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			return cb.Mgmt.Systems(ctx)
+		}
+	}
+
+	if cb.Mgmt.Pools != nil {
+		t["pools"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Search []string `json:"search"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.Mgmt.Pools(ctx, args.Search...)
+		}
+	}
+
+	if cb.Mgmt.PluginRegister != nil {
+		t["plugin_register"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args PluginRegister
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.Mgmt.PluginRegister(ctx, &args)
+		}
+	}
+
+	if cb.Mgmt.PluginUnregister != nil {
+		t["plugin_unregister"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			return nil, cb.Mgmt.PluginUnregister(ctx)
+		}
+	}
+
+	buildSanTable(t, cb)
+	buildFsTable(t, cb)
+	buildVolumeGroupTable(t, cb)
+	buildSnapshotScheduleTable(t, cb)
+
+	return t
+}
+
+// buildSanTable registers the SanOps methods cb has wired up.
+func buildSanTable(t map[string]handler, cb *PluginCallBacks) {
+	if cb.San.Volumes != nil {
+		t["volumes"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Search []string `json:"search"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.Volumes(ctx, args.Search...)
+		}
+	}
+
+	if cb.San.VolumeCreate != nil {
+		t["volume_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Pool         *Pool               `json:"pool"`
+				Name         string              `json:"volume_name"`
+				Size         uint64              `json:"size_bytes"`
+				Provisioning VolumeProvisionType `json:"provisioning"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			vol, jobID, err := cb.San.VolumeCreate(ctx, args.Pool, args.Name, args.Size, args.Provisioning)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				Volume *Volume `json:"volume"`
+				JobID  *string `json:"job_id"`
+			}{vol, jobID}, nil
+		}
+	}
+
+	if cb.San.VolumeDelete != nil {
+		t["volume_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume *Volume `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.San.VolumeDelete(ctx, args.Volume)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.San.Disks != nil {
+		t["disks"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			return cb.San.Disks(ctx)
+		}
+	}
+
+	if cb.San.VolumeReplicate != nil {
+		t["volume_replicate"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Pool    *Pool               `json:"pool"`
+				RepType VolumeReplicateType `json:"rep_type"`
+				SrcVol  *Volume             `json:"volume_src"`
+				Name    string              `json:"name"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			vol, jobID, err := cb.San.VolumeReplicate(ctx, args.Pool, args.RepType, args.SrcVol, args.Name)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				Volume *Volume `json:"volume"`
+				JobID  *string `json:"job_id"`
+			}{vol, jobID}, nil
+		}
+	}
+
+	if cb.San.VolumeReplicateRange != nil {
+		t["volume_replicate_range"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				RepType VolumeReplicateType `json:"rep_type"`
+				SrcVol  *Volume             `json:"volume_src"`
+				DstVol  *Volume             `json:"volume_dest"`
+				Ranges  []BlockRange        `json:"ranges"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.San.VolumeReplicateRange(ctx, args.RepType, args.SrcVol, args.DstVol, args.Ranges)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.San.VolumeRepRangeBlkSize != nil {
+		t["volume_rep_range_blk_size"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				System *System `json:"system"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.VolumeRepRangeBlkSize(ctx, args.System)
+		}
+	}
+
+	if cb.San.VolumeResize != nil {
+		t["volume_resize"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume      *Volume `json:"volume"`
+				NewSizeByte uint64  `json:"new_size_bytes"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			vol, jobID, err := cb.San.VolumeResize(ctx, args.Volume, args.NewSizeByte)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				Volume *Volume `json:"volume"`
+				JobID  *string `json:"job_id"`
+			}{vol, jobID}, nil
+		}
+	}
+
+	if cb.San.VolumeEnable != nil {
+		t["volume_enable"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume *Volume `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.San.VolumeEnable(ctx, args.Volume)
+		}
+	}
+
+	if cb.San.VolumeDisable != nil {
+		t["volume_disable"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume *Volume `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.San.VolumeDisable(ctx, args.Volume)
+		}
+	}
+
+	if cb.San.VolumeMask != nil {
+		t["volume_mask"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume      *Volume      `json:"volume"`
+				AccessGroup *AccessGroup `json:"access_group"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.San.VolumeMask(ctx, args.Volume, args.AccessGroup)
+		}
+	}
+
+	if cb.San.VolumeUnMask != nil {
+		t["volume_unmask"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume      *Volume      `json:"volume"`
+				AccessGroup *AccessGroup `json:"access_group"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.San.VolumeUnMask(ctx, args.Volume, args.AccessGroup)
+		}
+	}
+
+	if cb.San.VolsMaskedToAg != nil {
+		t["volumes_accessible_by_access_group"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				AccessGroup *AccessGroup `json:"access_group"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.VolsMaskedToAg(ctx, args.AccessGroup)
+		}
+	}
+
+	if cb.San.VolHasChildDep != nil {
+		t["volume_child_dependency"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume *Volume `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.VolHasChildDep(ctx, args.Volume)
+		}
+	}
+
+	if cb.San.VolChildDepRm != nil {
+		t["volume_child_dependency_rm"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume *Volume `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.San.VolChildDepRm(ctx, args.Volume)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.San.AccessGroups != nil {
+		t["access_groups"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			return cb.San.AccessGroups(ctx)
+		}
+	}
+
+	if cb.San.AccessGroupCreate != nil {
+		t["access_group_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Name     string        `json:"name"`
+				InitID   string        `json:"init_id"`
+				InitType InitiatorType `json:"init_type"`
+				System   *System       `json:"system"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.AccessGroupCreate(ctx, args.Name, args.InitID, args.InitType, args.System)
+		}
+	}
+
+	if cb.San.AccessGroupDelete != nil {
+		t["access_group_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				AccessGroup *AccessGroup `json:"access_group"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.San.AccessGroupDelete(ctx, args.AccessGroup)
+		}
+	}
+
+	if cb.San.AccessGroupInitAdd != nil {
+		t["access_group_initiator_add"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				AccessGroup *AccessGroup  `json:"access_group"`
+				InitID      string        `json:"init_id"`
+				InitType    InitiatorType `json:"init_type"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.AccessGroupInitAdd(ctx, args.AccessGroup, args.InitID, args.InitType)
+		}
+	}
+
+	if cb.San.AccessGroupInitDelete != nil {
+		t["access_group_initiator_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				AccessGroup *AccessGroup  `json:"access_group"`
+				InitID      string        `json:"init_id"`
+				InitType    InitiatorType `json:"init_type"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.AccessGroupInitDelete(ctx, args.AccessGroup, args.InitID, args.InitType)
+		}
+	}
+
+	if cb.San.AgsGrantedToVol != nil {
+		t["access_groups_granted_to_volume"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Volume *Volume `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.San.AgsGrantedToVol(ctx, args.Volume)
+		}
+	}
+
+	if cb.San.IscsiChapAuthSet != nil {
+		t["iscsi_chap_auth"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				InitID      string  `json:"init_id"`
+				InUser      *string `json:"in_user"`
+				InPassword  *string `json:"in_password"`
+				OutUser     *string `json:"out_user"`
+				OutPassword *string `json:"out_password"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.San.IscsiChapAuthSet(ctx, args.InitID, args.InUser, args.InPassword, args.OutUser, args.OutPassword)
+		}
+	}
+
+	if cb.San.TargetPorts != nil {
+		t["target_ports"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			return cb.San.TargetPorts(ctx)
+		}
+	}
+}
+
+// buildFsTable registers the FsOps methods cb has wired up.
+func buildFsTable(t map[string]handler, cb *PluginCallBacks) {
+	if cb.File.FileSystems != nil {
+		t["fs"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Search []string `json:"search"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.File.FileSystems(ctx, args.Search...)
+		}
+	}
+
+	if cb.File.FsCreate != nil {
+		t["fs_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Pool *Pool  `json:"pool"`
+				Name string `json:"name"`
+				Size uint64 `json:"size_bytes"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			fs, jobID, err := cb.File.FsCreate(ctx, args.Pool, args.Name, args.Size)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				FileSystem *FileSystem `json:"fs"`
+				JobID      *string     `json:"job_id"`
+			}{fs, jobID}, nil
+		}
+	}
+
+	if cb.File.FsDelete != nil {
+		t["fs_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem *FileSystem `json:"fs"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.File.FsDelete(ctx, args.FileSystem)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.File.FsResize != nil {
+		t["fs_resize"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem  *FileSystem `json:"fs"`
+				NewSizeByte uint64      `json:"new_size_bytes"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			fs, jobID, err := cb.File.FsResize(ctx, args.FileSystem, args.NewSizeByte)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				FileSystem *FileSystem `json:"fs"`
+				JobID      *string     `json:"job_id"`
+			}{fs, jobID}, nil
+		}
+	}
+
+	if cb.File.FsClone != nil {
+		t["fs_clone"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				SrcFs    *FileSystem         `json:"src_fs"`
+				DestName string              `json:"dest_fs_name"`
+				SnapShot *FileSystemSnapShot `json:"snapshot"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			fs, jobID, err := cb.File.FsClone(ctx, args.SrcFs, args.DestName, args.SnapShot)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				FileSystem *FileSystem `json:"fs"`
+				JobID      *string     `json:"job_id"`
+			}{fs, jobID}, nil
+		}
+	}
+
+	if cb.File.FsFileClone != nil {
+		t["fs_file_clone"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem  *FileSystem         `json:"fs"`
+				SrcFileName string              `json:"src_file_name"`
+				DstFileName string              `json:"dest_file_name"`
+				SnapShot    *FileSystemSnapShot `json:"snapshot"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.File.FsFileClone(ctx, args.FileSystem, args.SrcFileName, args.DstFileName, args.SnapShot)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.File.FsSnapShotCreate != nil {
+		t["fs_snapshot_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem *FileSystem `json:"fs"`
+				Name       string      `json:"snapshot_name"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			snap, jobID, err := cb.File.FsSnapShotCreate(ctx, args.FileSystem, args.Name)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				SnapShot *FileSystemSnapShot `json:"snapshot"`
+				JobID    *string             `json:"job_id"`
+			}{snap, jobID}, nil
+		}
+	}
+
+	if cb.File.FsSnapShotDelete != nil {
+		t["fs_snapshot_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem *FileSystem         `json:"fs"`
+				SnapShot   *FileSystemSnapShot `json:"snapshot"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.File.FsSnapShotDelete(ctx, args.FileSystem, args.SnapShot)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.File.FsSnapShots != nil {
+		t["fs_snapshots"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem *FileSystem `json:"fs"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.File.FsSnapShots(ctx, args.FileSystem)
+		}
+	}
+
+	if cb.File.FsSnapShotRestore != nil {
+		t["fs_snapshot_restore"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem   *FileSystem         `json:"fs"`
+				SnapShot     *FileSystemSnapShot `json:"snapshot"`
+				AllFiles     bool                `json:"all_files"`
+				Files        []string            `json:"files"`
+				RestoreFiles []string            `json:"restore_files"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.File.FsSnapShotRestore(ctx, args.FileSystem, args.SnapShot, args.AllFiles, args.Files, args.RestoreFiles)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.File.FsHasChildDep != nil {
+		t["fs_child_dependency"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				FileSystem *FileSystem `json:"fs"`
+				Files      []string    `json:"files"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.File.FsHasChildDep(ctx, args.FileSystem, args.Files)
+		}
+	}
+}
+
+// buildVolumeGroupTable registers the VolumeGroupOps methods cb has wired
+// up.  Method names match what client.go sends, so client and plugin stay
+// in sync on the wire.
+func buildVolumeGroupTable(t map[string]handler, cb *PluginCallBacks) {
+	if cb.VolGroup.VolumeGroups != nil {
+		t["volume_groups"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Search []string `json:"search"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.VolGroup.VolumeGroups(ctx, args.Search...)
+		}
+	}
+
+	if cb.VolGroup.VolumeGroupCreate != nil {
+		t["volume_group_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Name   string  `json:"name"`
+				System *System `json:"system"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.VolGroup.VolumeGroupCreate(ctx, args.Name, args.System)
+		}
+	}
+
+	if cb.VolGroup.VolumeGroupDelete != nil {
+		t["volume_group_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				VolumeGroup *VolumeGroup `json:"volume_group"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.VolGroup.VolumeGroupDelete(ctx, args.VolumeGroup)
+		}
+	}
+
+	if cb.VolGroup.VolumeGroupAddVol != nil {
+		t["volume_group_add_volume"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				VolumeGroup *VolumeGroup `json:"volume_group"`
+				Volume      *Volume      `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.VolGroup.VolumeGroupAddVol(ctx, args.VolumeGroup, args.Volume)
+		}
+	}
+
+	if cb.VolGroup.VolumeGroupRemoveVol != nil {
+		t["volume_group_remove_volume"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				VolumeGroup *VolumeGroup `json:"volume_group"`
+				Volume      *Volume      `json:"volume"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.VolGroup.VolumeGroupRemoveVol(ctx, args.VolumeGroup, args.Volume)
+		}
+	}
+
+	if cb.VolGroup.SnapShotCreate != nil {
+		t["volume_group_snap_shot_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Group *VolumeGroup `json:"group"`
+				Name  string       `json:"name"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			snap, volumes, jobID, err := cb.VolGroup.SnapShotCreate(ctx, args.Group, args.Name)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				SnapShot *VolumeGroupSnapshot `json:"snap_shot"`
+				Volumes  []Volume             `json:"volumes"`
+				JobID    *string              `json:"job_id"`
+			}{snap, volumes, jobID}, nil
+		}
+	}
+
+	if cb.VolGroup.SnapShotDelete != nil {
+		t["volume_group_snap_shot_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				VolumeGroup *VolumeGroup         `json:"volume_group"`
+				SnapShot    *VolumeGroupSnapshot `json:"snap_shot"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.VolGroup.SnapShotDelete(ctx, args.VolumeGroup, args.SnapShot)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.VolGroup.SnapShotRestore != nil {
+		t["volume_group_snap_shot_restore"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				VolumeGroup *VolumeGroup         `json:"volume_group"`
+				SnapShot    *VolumeGroupSnapshot `json:"snap_shot"`
+				Volumes     []Volume             `json:"volumes"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.VolGroup.SnapShotRestore(ctx, args.VolumeGroup, args.SnapShot, args.Volumes)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+
+	if cb.VolGroup.SnapShots != nil {
+		t["volume_group_snap_shots"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				VolumeGroup *VolumeGroup `json:"volume_group"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.VolGroup.SnapShots(ctx, args.VolumeGroup)
+		}
+	}
+}
+
+// buildSnapshotScheduleTable registers the SnapshotScheduleOps methods cb
+// has wired up, whether a plugin supplied them itself or newPlugin wired
+// them to the default Scheduler.
+func buildSnapshotScheduleTable(t map[string]handler, cb *PluginCallBacks) {
+	if cb.Schedule.SnapshotScheduleCreate != nil {
+		t["snapshot_schedule_create"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Name      string          `json:"name"`
+				Target    SnapshotTarget  `json:"target"`
+				Cron      string          `json:"cron"`
+				Retention RetentionPolicy `json:"retention"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.Schedule.SnapshotScheduleCreate(ctx, args.Name, args.Target, args.Cron, args.Retention)
+		}
+	}
+
+	if cb.Schedule.SnapshotScheduleDelete != nil {
+		t["snapshot_schedule_delete"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Schedule *SnapshotSchedule `json:"schedule"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return nil, cb.Schedule.SnapshotScheduleDelete(ctx, args.Schedule)
+		}
+	}
+
+	if cb.Schedule.SnapshotSchedules != nil {
+		t["snapshot_schedules"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Search []string `json:"search"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			return cb.Schedule.SnapshotSchedules(ctx, args.Search...)
+		}
+	}
+
+	if cb.Schedule.SnapshotScheduleRunNow != nil {
+		t["snapshot_schedule_run_now"] = func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error) {
+			var args struct {
+				Schedule *SnapshotSchedule `json:"schedule"`
+			}
+			if err := decodeParams(params, &args); err != nil {
+				return nil, err
+			}
+			jobID, err := cb.Schedule.SnapshotScheduleRunNow(ctx, args.Schedule)
+			return struct {
+				JobID *string `json:"job_id"`
+			}{jobID}, err
+		}
+	}
+}
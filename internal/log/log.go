@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: 0BSD
+
+// Package log provides the small, structured logging facility used by
+// Plugin.Run in place of ad-hoc fmt.Printf calls.  Plugin authors can
+// install their own Logger via Plugin.SetLogger to route plugin RPC
+// activity into whatever logging system their deployment already uses.
+package log
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Level is the severity of a single log entry.
+type Level int
+
+// The set of levels honored by DefaultLog, DebugLog, TraceLog and
+// ErrorLog, from least to most verbose.
+const (
+	LevelError Level = iota
+	LevelDefault
+	LevelDebug
+	LevelTrace
+)
+
+// String returns the lower case name of the level, as used by
+// LSM_LOG_LEVEL.
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "error"
+	case LevelDefault:
+		return "default"
+	case LevelDebug:
+		return "debug"
+	case LevelTrace:
+		return "trace"
+	default:
+		return "unknown"
+	}
+}
+
+// Field is a single piece of structured context attached to a log entry,
+// e.g. method, jobID or duration.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F builds a Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger is implemented by anything that can receive a leveled, structured
+// log entry.  Plugin authors supply their own via Plugin.SetLogger to
+// integrate with an existing logging stack.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// stdLogger is the default Logger, writing filtered entries to stderr.
+type stdLogger struct {
+	level Level
+	out   *log.Logger
+}
+
+// New returns a Logger that writes to stderr, dropping any entry more
+// verbose than level.
+func New(level Level) Logger {
+	return &stdLogger{level: level, out: log.New(os.Stderr, "", log.LstdFlags)}
+}
+
+func (s *stdLogger) Log(level Level, msg string, fields ...Field) {
+	if level > s.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	s.out.Println(b.String())
+}
+
+// LevelFromEnv parses the LSM_LOG_LEVEL environment variable
+// ("error", "default", "debug" or "trace"), defaulting to LevelDefault
+// when it is unset or unrecognized.
+func LevelFromEnv() Level {
+	switch strings.ToLower(os.Getenv("LSM_LOG_LEVEL")) {
+	case "error":
+		return LevelError
+	case "debug":
+		return LevelDebug
+	case "trace":
+		return LevelTrace
+	default:
+		return LevelDefault
+	}
+}
+
+var defaultLogger = New(LevelFromEnv())
+
+// SetDefault installs l as the package-wide logger used by DefaultLog,
+// DebugLog, TraceLog and ErrorLog.
+func SetDefault(l Logger) {
+	defaultLogger = l
+}
+
+// DefaultLog logs msg at LevelDefault.
+func DefaultLog(msg string, fields ...Field) {
+	defaultLogger.Log(LevelDefault, msg, fields...)
+}
+
+// DebugLog logs msg at LevelDebug.
+func DebugLog(msg string, fields ...Field) {
+	defaultLogger.Log(LevelDebug, msg, fields...)
+}
+
+// TraceLog logs msg at LevelTrace.
+func TraceLog(msg string, fields ...Field) {
+	defaultLogger.Log(LevelTrace, msg, fields...)
+}
+
+// ErrorLog logs msg at LevelError.
+func ErrorLog(msg string, fields ...Field) {
+	defaultLogger.Log(LevelError, msg, fields...)
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: 0BSD
+
+package libstoragemgmt
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	errors "github.com/libstorage/libstoragemgmt-golang/errors"
+)
+
+// JobStatusType and its JobInProgress/JobComplete/JobError values are
+// defined elsewhere in package libstoragemgmt (JobInfo.Status already uses
+// JobStatusType); JobManager only consumes them here.
+
+// defaultJobTTL is how long a completed job's result is kept around for a
+// client that hasn't yet called JobFree, before JobManager reclaims it.
+const defaultJobTTL = 30 * time.Minute
+
+// job tracks the state of a single in-flight or completed async operation.
+type job struct {
+	kind    string
+	status  JobStatusType
+	percent uint8
+	result  interface{}
+	err     error
+	expires time.Time
+}
+
+// JobManager tracks asynchronous plugin operations so a plugin does not
+// need to reinvent job-id bookkeeping for every callback that can return
+// one.  Plugins embed a JobManager, call Submit from a callback that would
+// otherwise run synchronously, and wire JobStatus/JobFree to its
+// JobStatusCb/JobFreeCb methods.
+type JobManager struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	ttl  time.Duration
+}
+
+// NewJobManager returns a JobManager whose completed jobs are reclaimed
+// ttl after they finish if the client never calls JobFree.  A ttl of zero
+// uses defaultJobTTL.
+func NewJobManager(ttl time.Duration) *JobManager {
+	if ttl == 0 {
+		ttl = defaultJobTTL
+	}
+	return &JobManager{jobs: make(map[string]*job), ttl: ttl}
+}
+
+func newJobID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", buf), nil
+}
+
+// Submit runs fn in its own goroutine, decoupled from ctx, and returns a
+// job id that a client can poll via JobStatus and release via JobFree.
+// ctx is only checked for cancellation before the job starts; the job itself
+// keeps running to completion even after the callback that submitted it,
+// and the request's own context, has returned. fn is passed a progress
+// function it may call any number of times with a 0-100 percent-complete
+// value; the most recent value is reported back to the client. The job
+// is recorded as failed only when fn itself returns a non-nil error.
+func (jm *JobManager) Submit(ctx context.Context, kind string, fn func(progress func(uint8)) (interface{}, error)) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return "", &errors.LsmError{Code: errors.LibBug, Message: err.Error()}
+	}
+
+	j := &job{kind: kind, status: JobInProgress}
+	jm.mu.Lock()
+	jm.jobs[id] = j
+	jm.mu.Unlock()
+
+	progress := func(percent uint8) {
+		jm.mu.Lock()
+		j.percent = percent
+		jm.mu.Unlock()
+	}
+
+	go func() {
+		result, err := fn(progress)
+
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+
+		if err != nil {
+			j.status = JobError
+			j.err = err
+		} else {
+			j.status = JobComplete
+			j.percent = 100
+			j.result = result
+		}
+		j.expires = time.Now().Add(jm.ttl)
+	}()
+
+	return id, nil
+}
+
+// Status returns the job status for id, implementing the bulk of
+// JobStatusCb for an embedding plugin.
+func (jm *JobManager) Status(jobID string) (*JobInfo, error) {
+	jm.reap()
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	j, ok := jm.jobs[jobID]
+	if !ok {
+		return nil, &errors.LsmError{Code: errors.NotFoundJob, Message: fmt.Sprintf("job %s not found", jobID)}
+	}
+
+	if j.status == JobError {
+		return nil, j.err
+	}
+
+	return &JobInfo{Status: j.status, Percent: j.percent, Item: j.result}, nil
+}
+
+// Free releases the resources held for a completed job, implementing
+// JobFreeCb for an embedding plugin.
+func (jm *JobManager) Free(jobID string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	delete(jm.jobs, jobID)
+	return nil
+}
+
+// reap drops completed jobs whose ttl has elapsed without a JobFree call.
+func (jm *JobManager) reap() {
+	now := time.Now()
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	for id, j := range jm.jobs {
+		if j.status != JobInProgress && now.After(j.expires) {
+			delete(jm.jobs, id)
+		}
+	}
+}
+
+// JobStatusCb adapts Status to the JobStatusCb signature for plugins that
+// want to wire a JobManager directly into ManagementOps.JobStatus.
+func (jm *JobManager) JobStatusCb(ctx context.Context, jobID string) (*JobInfo, error) {
+	return jm.Status(jobID)
+}
+
+// JobFreeCb adapts Free to the JobFreeCb signature for plugins that want
+// to wire a JobManager directly into ManagementOps.JobFree.
+func (jm *JobManager) JobFreeCb(ctx context.Context, jobID string) error {
+	return jm.Free(jobID)
+}
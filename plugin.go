@@ -3,23 +3,26 @@
 package libstoragemgmt
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"strconv"
+	"time"
 
 	errors "github.com/libstorage/libstoragemgmt-golang/errors"
+	"github.com/libstorage/libstoragemgmt-golang/internal/log"
 )
 
 // TmoSetCb used to register timeout value for plugin
-type TmoSetCb func(timeout uint32) error
+type TmoSetCb func(ctx context.Context, timeout uint32) error
 
 // TmoGetCb used to register timeout value for plugin
-type TmoGetCb func() uint32
+type TmoGetCb func(ctx context.Context) uint32
 
 // CapabilitiesCb returns what the plugin is capable of
-type CapabilitiesCb func(system *System) (*Capabilities, error)
+type CapabilitiesCb func(ctx context.Context, system *System) (*Capabilities, error)
 
 // JobInfo is the information about a job
 type JobInfo struct {
@@ -29,98 +32,98 @@ type JobInfo struct {
 }
 
 // JobStatusCb callback returns the job status for the specified job
-type JobStatusCb func(jobId string) (*JobInfo, error)
+type JobStatusCb func(ctx context.Context, jobId string) (*JobInfo, error)
 
 // JobFreeCb callback for freeing job resources
-type JobFreeCb func(jobID string) error
+type JobFreeCb func(ctx context.Context, jobID string) error
 
 // PoolsCb callback for pools
-type PoolsCb func(search ...string) ([]Pool, error)
+type PoolsCb func(ctx context.Context, search ...string) ([]Pool, error)
 
 // PluginRegisterCb callback to register needed information
-type PluginRegisterCb func(p *PluginRegister) error
+type PluginRegisterCb func(ctx context.Context, p *PluginRegister) error
 
 // PluginUnregisterCb callback to allow plugin to cleanup resources
-type PluginUnregisterCb func() error
+type PluginUnregisterCb func(ctx context.Context) error
 
 //SystemsCb callback to retrieve systems
-type SystemsCb func() ([]System, error)
+type SystemsCb func(ctx context.Context) ([]System, error)
 
 //DisksCb callback to retrieve disks
-type DisksCb func() ([]Disk, error)
+type DisksCb func(ctx context.Context) ([]Disk, error)
 
 //VolumesCb callback to retrieve volumes
-type VolumesCb func(search ...string) ([]Volume, error)
+type VolumesCb func(ctx context.Context, search ...string) ([]Volume, error)
 
 // VolumeCreateCb callback is for creating a volume
-type VolumeCreateCb func(pool *Pool,
+type VolumeCreateCb func(ctx context.Context, pool *Pool,
 	volumeName string,
 	size uint64,
 	provisioning VolumeProvisionType) (*Volume, *string, error)
 
 // VolumeDeleteCb callback is for deleting a volume
-type VolumeDeleteCb func(vol *Volume) (*string, error)
+type VolumeDeleteCb func(ctx context.Context, vol *Volume) (*string, error)
 
 // VolumeReplicateCb returns volume, job id, error.
-type VolumeReplicateCb func(optionalPool *Pool, repType VolumeReplicateType,
+type VolumeReplicateCb func(ctx context.Context, optionalPool *Pool, repType VolumeReplicateType,
 	sourceVolume *Volume, name string) (*Volume, *string, error)
 
 // VolumeReplicateRangeCb returns job id, error
-type VolumeReplicateRangeCb func(repType VolumeReplicateType, srcVol *Volume, dstVol *Volume,
+type VolumeReplicateRangeCb func(ctx context.Context, repType VolumeReplicateType, srcVol *Volume, dstVol *Volume,
 	ranges []BlockRange) (*string, error)
 
 // VolumeRepRangeBlkSizeCb returns blocksize, error
-type VolumeRepRangeBlkSizeCb func(system *System) (uint32, error)
+type VolumeRepRangeBlkSizeCb func(ctx context.Context, system *System) (uint32, error)
 
 // VolumeResizeCb returns volume, job id, error
-type VolumeResizeCb func(vol *Volume, newSizeBytes uint64) (*Volume, *string, error)
+type VolumeResizeCb func(ctx context.Context, vol *Volume, newSizeBytes uint64) (*Volume, *string, error)
 
 // VolumeEnableCb enables a volume
-type VolumeEnableCb func(vol *Volume) error
+type VolumeEnableCb func(ctx context.Context, vol *Volume) error
 
 // VolumeDisableCb enables a volume
-type VolumeDisableCb func(vol *Volume) error
+type VolumeDisableCb func(ctx context.Context, vol *Volume) error
 
 // VolumeMaskCb maskes a volume to the associated access group
-type VolumeMaskCb func(vol *Volume, ag *AccessGroup) error
+type VolumeMaskCb func(ctx context.Context, vol *Volume, ag *AccessGroup) error
 
 // VolumeUnMaskCb unmaskes a volume from the associated access group
-type VolumeUnMaskCb func(vol *Volume, ag *AccessGroup) error
+type VolumeUnMaskCb func(ctx context.Context, vol *Volume, ag *AccessGroup) error
 
 // VolsMaskedToAgCb returns those volumes accessible from specified access group
-type VolsMaskedToAgCb func(ag *AccessGroup) ([]Volume, error)
+type VolsMaskedToAgCb func(ctx context.Context, ag *AccessGroup) ([]Volume, error)
 
 // AgsGrantedToVolCb returns access group(s) which have access to specified volume
-type AgsGrantedToVolCb func(vol *Volume) ([]AccessGroup, error)
+type AgsGrantedToVolCb func(ctx context.Context, vol *Volume) ([]AccessGroup, error)
 
 // AccessGroupsCb returns all the access groups
-type AccessGroupsCb func() ([]AccessGroup, error)
+type AccessGroupsCb func(ctx context.Context) ([]AccessGroup, error)
 
 // AccessGroupCreateCb creates an access group
-type AccessGroupCreateCb func(name string, initID string, initType InitiatorType, system *System) (*AccessGroup, error)
+type AccessGroupCreateCb func(ctx context.Context, name string, initID string, initType InitiatorType, system *System) (*AccessGroup, error)
 
 // AccessGroupDeleteCb deletes an access group
-type AccessGroupDeleteCb func(ag *AccessGroup) error
+type AccessGroupDeleteCb func(ctx context.Context, ag *AccessGroup) error
 
 // AccessGroupInitAddCb adds an initiator to an AccessGroup
-type AccessGroupInitAddCb func(ag *AccessGroup,
+type AccessGroupInitAddCb func(ctx context.Context, ag *AccessGroup,
 	initID string, initType InitiatorType) (*AccessGroup, error)
 
 // AccessGroupInitDeleteCb removes an initiator from an AccessGroup
-type AccessGroupInitDeleteCb func(ag *AccessGroup,
+type AccessGroupInitDeleteCb func(ctx context.Context, ag *AccessGroup,
 	initID string, initType InitiatorType) (*AccessGroup, error)
 
 // IscsiChapAuthSetCb iSCSI CHAP authentication
-type IscsiChapAuthSetCb func(initID string, inUser *string, inPassword *string, outUser *string, outPassword *string) error
+type IscsiChapAuthSetCb func(ctx context.Context, initID string, inUser *string, inPassword *string, outUser *string, outPassword *string) error
 
 // VolHasChildDepCb returns boolean on if specified volume has child dependencies
-type VolHasChildDepCb func(vol *Volume) (bool, error)
+type VolHasChildDepCb func(ctx context.Context, vol *Volume) (bool, error)
 
 // VolChildDepRmCb removes any child dependencies
-type VolChildDepRmCb func(vol *Volume) (*string, error)
+type VolChildDepRmCb func(ctx context.Context, vol *Volume) (*string, error)
 
 // TargetPortsCb returns target ports
-type TargetPortsCb func() ([]TargetPort, error)
+type TargetPortsCb func(ctx context.Context) ([]TargetPort, error)
 
 // ManagementOps are the callbacks that plugins must implement
 type ManagementOps struct {
@@ -163,44 +166,44 @@ type SanOps struct {
 }
 
 // FsCb callback returns filesystems
-type FsCb func(search ...string) ([]FileSystem, error)
+type FsCb func(ctx context.Context, search ...string) ([]FileSystem, error)
 
 // FsCreateCb callback creates a file system
-type FsCreateCb func(pool *Pool, name string, size uint64) (*FileSystem, *string, error)
+type FsCreateCb func(ctx context.Context, pool *Pool, name string, size uint64) (*FileSystem, *string, error)
 
 // FsDeleteCb callback deletes a file system
-type FsDeleteCb func(fs *FileSystem) (*string, error)
+type FsDeleteCb func(ctx context.Context, fs *FileSystem) (*string, error)
 
 // FsResizeCb callback resizes a file system
-type FsResizeCb func(fs *FileSystem, newSizeBytes uint64) (*FileSystem, *string, error)
+type FsResizeCb func(ctx context.Context, fs *FileSystem, newSizeBytes uint64) (*FileSystem, *string, error)
 
 // FsCloneCb callback clones a file system
-type FsCloneCb func(srcFs *FileSystem,
+type FsCloneCb func(ctx context.Context, srcFs *FileSystem,
 	destName string,
 	optionalSnapShot *FileSystemSnapShot) (*FileSystem, *string, error)
 
 // FsFileCloneCb callback snap shots files on a file system
-type FsFileCloneCb func(fs *FileSystem,
+type FsFileCloneCb func(ctx context.Context, fs *FileSystem,
 	srcFileName string,
 	dstFileName string,
 	optionalSnapShot *FileSystemSnapShot) (*string, error)
 
 // FsSnapShotCreateCb callback creates a snapshot
-type FsSnapShotCreateCb func(s *FileSystem, name string) (*FileSystemSnapShot, *string, error)
+type FsSnapShotCreateCb func(ctx context.Context, s *FileSystem, name string) (*FileSystemSnapShot, *string, error)
 
 // FsSnapShotDeleteCb callback deletes a snapshot
-type FsSnapShotDeleteCb func(fs *FileSystem, snapShot *FileSystemSnapShot) (*string, error)
+type FsSnapShotDeleteCb func(ctx context.Context, fs *FileSystem, snapShot *FileSystemSnapShot) (*string, error)
 
 // FsSnapShotsCb callback returns array of file system snapshots
-type FsSnapShotsCb func(fs *FileSystem) ([]FileSystemSnapShot, error)
+type FsSnapShotsCb func(ctx context.Context, fs *FileSystem) ([]FileSystemSnapShot, error)
 
 // FsSnapShotRestoreCb callback restores a file system from a snapshot
-type FsSnapShotRestoreCb func(
+type FsSnapShotRestoreCb func(ctx context.Context,
 	fs *FileSystem, snapShot *FileSystemSnapShot, allFiles bool,
 	files []string, restoreFiles []string) (*string, error)
 
 // FsHasChildDepCb callback returns boolean indicating if filesystem has child dependencies
-type FsHasChildDepCb func(fs *FileSystem, files []string) (bool, error)
+type FsHasChildDepCb func(ctx context.Context, fs *FileSystem, files []string) (bool, error)
 
 // FsOps file system callbacks
 type FsOps struct {
@@ -219,12 +222,14 @@ type FsOps struct {
 
 // PluginCallBacks callbacks for plugin to implement
 type PluginCallBacks struct {
-	Mgmt ManagementOps
-	San  SanOps
-	File FsOps
+	Mgmt     ManagementOps
+	San      SanOps
+	File     FsOps
+	VolGroup VolumeGroupOps
+	Schedule SnapshotScheduleOps
 }
 
-type handler func(p *Plugin, params json.RawMessage) (interface{}, error)
+type handler func(ctx context.Context, p *Plugin, params json.RawMessage) (interface{}, error)
 
 // Plugin represents plugin
 type Plugin struct {
@@ -233,6 +238,19 @@ type Plugin struct {
 	callTable map[string]handler
 	desc      string
 	ver       string
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout uint32
+
+	log  log.Logger
+	Jobs *JobManager
+}
+
+// SetLogger installs l as the logger used for this plugin's RPC activity,
+// replacing the default stderr logger.  Call it before Run.
+func (p *Plugin) SetLogger(l log.Logger) {
+	p.log = l
 }
 
 // PluginRegister data passed to PluginRegister callback
@@ -243,9 +261,77 @@ type PluginRegister struct {
 	Flags    uint32
 }
 
+// verbosityFromArgs scans the flags following the socket fd for a
+// recognized verbosity switch, falling back to LSM_LOG_LEVEL when none is
+// present.
+func verbosityFromArgs(cmdLineArgs []string) log.Level {
+	level := log.LevelFromEnv()
+	for _, a := range cmdLineArgs {
+		switch a {
+		case "-v", "--verbose", "--debug":
+			level = log.LevelDebug
+		case "-t", "--trace":
+			level = log.LevelTrace
+		}
+	}
+	return level
+}
+
+// newPlugin assembles a Plugin around an already-established transport
+// connection.  It is shared by PluginInit and PluginInitWithOpts so both
+// entry points get the same job manager, logger and context wiring.
+func newPlugin(callbacks *PluginCallBacks, conn net.Conn, debug bool, level log.Level, desc string, ver string) *Plugin {
+	tp := transPort{uds: conn, debug: debug}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	jobs := NewJobManager(0)
+	if callbacks.Mgmt.JobStatus == nil {
+		callbacks.Mgmt.JobStatus = jobs.JobStatusCb
+	}
+	if callbacks.Mgmt.JobFree == nil {
+		callbacks.Mgmt.JobFree = jobs.JobFreeCb
+	}
+
+	if callbacks.Schedule.UseDefaultScheduler &&
+		callbacks.Schedule.SnapshotScheduleCreate == nil &&
+		callbacks.Schedule.SnapshotScheduleDelete == nil &&
+		callbacks.Schedule.SnapshotSchedules == nil &&
+		callbacks.Schedule.SnapshotScheduleRunNow == nil {
+		scheduler := NewScheduler(callbacks)
+		callbacks.Schedule.SnapshotScheduleCreate = func(ctx context.Context, name string, target SnapshotTarget,
+			cron string, retention RetentionPolicy) (*SnapshotSchedule, error) {
+			return scheduler.Create(name, target, cron, retention)
+		}
+		callbacks.Schedule.SnapshotScheduleDelete = func(ctx context.Context, sched *SnapshotSchedule) error {
+			return scheduler.Delete(sched)
+		}
+		callbacks.Schedule.SnapshotSchedules = func(ctx context.Context, search ...string) ([]SnapshotSchedule, error) {
+			return scheduler.Schedules()
+		}
+		callbacks.Schedule.SnapshotScheduleRunNow = scheduler.RunNow
+		scheduler.Start(ctx, 0)
+	}
+
+	return &Plugin{
+		tp:        tp,
+		cb:        callbacks,
+		callTable: buildTable(callbacks),
+		desc:      desc,
+		ver:       ver,
+		ctx:       ctx,
+		cancel:    cancel,
+		log:       log.New(level),
+		Jobs:      jobs,
+	}
+}
+
 // PluginInit initializes the plugin with the specified callbacks
 func PluginInit(callbacks *PluginCallBacks, cmdLineArgs []string, desc string, ver string) (*Plugin, error) {
-	if len(cmdLineArgs) == 2 {
+	if err := validateCallbacks(callbacks); err != nil {
+		return nil, err
+	}
+
+	if len(cmdLineArgs) >= 2 {
 		fd, err := strconv.ParseInt(cmdLineArgs[1], 10, 32)
 		if err != nil {
 			return nil, err
@@ -259,58 +345,130 @@ func PluginInit(callbacks *PluginCallBacks, cmdLineArgs []string, desc string, v
 			return nil, err
 		}
 
-		tp := transPort{uds: s, debug: false}
-		return &Plugin{tp: tp, cb: callbacks, callTable: buildTable(callbacks), desc: desc, ver: ver}, nil
+		return newPlugin(callbacks, s, false, verbosityFromArgs(cmdLineArgs[2:]), desc, ver), nil
 	}
 	return nil, &errors.LsmError{
 		Code:    errors.LibBug,
 		Message: fmt.Sprintf("Plugin called with invalid args: %s\n", cmdLineArgs)}
 }
 
-func noSupport(tp *transPort, method string) {
-	tp.sendError(&errors.LsmError{
+func noSupport(p *Plugin, method string) {
+	p.log.Log(log.LevelError, "method not supported", log.F("method", method))
+	p.tp.sendError(&errors.LsmError{
 		Code: errors.NoSupport,
 		Message: fmt.Sprintf(
 			"method %s not supported", method)})
 }
 
+// requestContext derives a per-request context from the plugin's base
+// context, bounding it to the timeout most recently registered through
+// TimeOutSet so a stalled callback can be abandoned once the client's own
+// deadline has passed.  Because it is derived from p.ctx, it is also
+// canceled the instant readLoop observes the transport disconnect, even
+// while the callback using it is still running.  The returned cancel must
+// be called once the request has been handled.
+func (p *Plugin) requestContext() (context.Context, context.CancelFunc) {
+	if p.timeout == 0 {
+		return context.WithCancel(p.ctx)
+	}
+	return context.WithTimeout(p.ctx, time.Duration(p.timeout)*time.Millisecond)
+}
+
+// noteTimeOutSet records the timeout requested by a time_out_set call so
+// that subsequent requests are bounded by it.
+func (p *Plugin) noteTimeOutSet(params json.RawMessage) {
+	var args struct {
+		Timeout uint32 `json:"timeout"`
+	}
+	if err := json.Unmarshal(params, &args); err == nil {
+		p.timeout = args.Timeout
+	}
+}
+
 // Run the plugin, looping processing requests and sending responses.
+//
+// Reading the transport happens on a dedicated goroutine (readLoop) so
+// that a disconnect is observed the moment it happens rather than only
+// once Run is ready to read the next request: readLoop is always blocked
+// in its next read while Run dispatches the current one, and a read
+// error there cancels p.ctx right away, which propagates to whatever
+// reqCtx the in-flight callback is using.
 func (p *Plugin) Run() {
+	defer p.cancel()
+
+	dispatch := make(chan func() bool, 1)
+	go p.readLoop(dispatch)
+
+	for d := range dispatch {
+		if d() {
+			return
+		}
+	}
+}
+
+// readLoop is Run's sole reader of the transport. It hands each
+// successfully read request to Run as a closure, sent over dispatch,
+// that returns whether Run should stop. Keeping reading on one dedicated
+// goroutine means there is never more than one in-flight read, so a
+// disconnect is caught as soon as it happens instead of only between
+// requests.
+func (p *Plugin) readLoop(dispatch chan<- func() bool) {
+	defer close(dispatch)
+
 	for {
 		request, err := p.tp.readRequest()
 		if err != nil {
 			if lsmError, ok := err.(*errors.LsmError); ok == true {
-
 				if lsmError.Code != errors.TransPortComunication {
-					p.tp.sendError(lsmError)
-					//fmt.Printf("Returned error %+v\n", lsmError)
+					dispatch <- func() bool {
+						p.tp.sendError(lsmError)
+						p.log.Log(log.LevelDebug, "returned error", log.F("error", lsmError))
+						return false
+					}
 					continue
-				} else {
-					fmt.Printf("Communication error: exiting! %s\n", lsmError)
 				}
+				p.log.Log(log.LevelError, "communication error, exiting", log.F("error", lsmError))
+				p.cancel()
 				return
 			}
-			fmt.Printf("Unexpected error, exiting! %s\n", err)
+			p.log.Log(log.LevelError, "unexpected error, exiting", log.F("error", err))
+			p.cancel()
 			return
 		}
 
-		var response interface{}
-		if f, ok := p.callTable[request.Method]; ok == true && f != nil {
-			//fmt.Printf("Executing %s(%s)\n", request.Method, string(request.Params))
-			response, err = f(p, request.Params)
+		dispatch <- func() bool {
+			f, ok := p.callTable[request.Method]
+			if !ok || f == nil {
+				noSupport(p, request.Method)
+				return false
+			}
+
+			p.log.Log(log.LevelTrace, "executing", log.F("method", request.Method), log.F("params", string(request.Params)))
+			if request.Method == "time_out_set" {
+				p.noteTimeOutSet(request.Params)
+			}
+
+			reqCtx, cancel := p.requestContext()
+			start := time.Now()
+			response, err := f(reqCtx, p, request.Params)
+			cancel()
+			duration := time.Since(start)
+
 			if err != nil {
 				p.tp.sendError(err)
+				p.log.Log(log.LevelDebug, "returned error", log.F("method", request.Method),
+					log.F("duration", duration), log.F("error", err))
 			} else {
 				p.tp.sendResponse(response)
+				p.log.Log(log.LevelTrace, "completed", log.F("method", request.Method), log.F("duration", duration))
 			}
 
 			// Need to shut down the connection.
 			if request.Method == "plugin_unregister" {
 				p.tp.close()
-				return
+				return true
 			}
-		} else {
-			noSupport(&p.tp, request.Method)
+			return false
 		}
 	}
 }
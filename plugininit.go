@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: 0BSD
+
+package libstoragemgmt
+
+import (
+	"context"
+	"net"
+
+	errors "github.com/libstorage/libstoragemgmt-golang/errors"
+)
+
+// CapabilityPruner clears the Capabilities bits that correspond to
+// callbacks a plugin left nil, so CapabilitiesCb never advertises support
+// it cannot actually provide.  Plugins that want this enforced pass one to
+// PluginOptions.Pruner; it is called with the callbacks the plugin
+// registered and the Capabilities its own CapabilitiesCb just produced.
+//
+// This package does not ship a built-in nil-callback-to-capability-bit
+// mapping: Capabilities is defined outside this tree, and hardcoding a
+// guess at its bit layout here risks silently clearing the wrong bits.
+// Plugin authors must supply the mapping for their own callback set via
+// Pruner until that layout is available to depend on directly.
+type CapabilityPruner func(cb *PluginCallBacks, caps *Capabilities)
+
+// PluginOptions configures PluginInitWithOpts.  It is a superset of what
+// PluginInit supports: a socket path rather than just an inherited fd, an
+// optional listen mode for out-of-process test harnesses, and a debug
+// switch for the wire protocol.
+type PluginOptions struct {
+	// CmdLineArgs mirrors the argv passed to PluginInit.  The fd is only
+	// taken from it when SocketPath is empty; its verbosity flags are
+	// honored either way.
+	CmdLineArgs []string
+
+	// SocketPath, when set, overrides the fd-based transport PluginInit
+	// uses with a direct unix domain socket path.
+	SocketPath string
+
+	// UnixListen, when SocketPath is set, makes the plugin listen on and
+	// accept a single connection on SocketPath instead of dialing it.
+	// This lets a standalone test harness drive the plugin binary out of
+	// process without lsmd brokering the fd handoff.
+	UnixListen bool
+
+	// Debug flips transPort.debug, logging each request and response on
+	// the wire.
+	Debug bool
+
+	// Pruner, if set, is run once CapabilitiesCb returns so advertised
+	// capabilities never outrun the callbacks PluginCallBacks actually
+	// wires up.  See CapabilityPruner for why this isn't automatic.
+	Pruner CapabilityPruner
+}
+
+// validationRule describes one inconsistent combination of callbacks that
+// validateCallbacks rejects: Have must be non-nil while Want is nil.
+type validationRule struct {
+	have, want string
+	present    func(cb *PluginCallBacks) bool
+}
+
+var validationRules = []validationRule{
+	{
+		have: "San.VolumeReplicate", want: "San.VolumeRepRangeBlkSize",
+		present: func(cb *PluginCallBacks) bool {
+			return cb.San.VolumeReplicate != nil && cb.San.VolumeRepRangeBlkSize == nil
+		},
+	},
+	{
+		have: "San.VolumeReplicateRange", want: "San.VolumeRepRangeBlkSize",
+		present: func(cb *PluginCallBacks) bool {
+			return cb.San.VolumeReplicateRange != nil && cb.San.VolumeRepRangeBlkSize == nil
+		},
+	},
+	{
+		have: "San.VolumeMask", want: "San.VolumeUnMask",
+		present: func(cb *PluginCallBacks) bool {
+			return cb.San.VolumeMask != nil && cb.San.VolumeUnMask == nil
+		},
+	},
+	{
+		have: "San.AccessGroupInitAdd", want: "San.AccessGroupInitDelete",
+		present: func(cb *PluginCallBacks) bool {
+			return cb.San.AccessGroupInitAdd != nil && cb.San.AccessGroupInitDelete == nil
+		},
+	},
+}
+
+// validateCallbacks rejects PluginCallBacks whose registered callbacks
+// advertise a capability (e.g. replication) without the supporting
+// callback it depends on (e.g. the replicated block size).
+func validateCallbacks(cb *PluginCallBacks) error {
+	for _, rule := range validationRules {
+		if rule.present(cb) {
+			return &errors.LsmError{
+				Code: errors.LibBug,
+				Message: "inconsistent plugin callbacks: " + rule.have +
+					" is set but " + rule.want + " is not",
+			}
+		}
+	}
+	return nil
+}
+
+// wrapCapabilitiesCb wraps cb.Mgmt.Capabilities, if set, so pruner runs on
+// every result it returns.  pruner is responsible for the entire
+// nil-callback-to-capability-bit mapping; nothing is cleared automatically.
+func wrapCapabilitiesCb(cb *PluginCallBacks, pruner CapabilityPruner) {
+	if pruner == nil || cb.Mgmt.Capabilities == nil {
+		return
+	}
+
+	inner := cb.Mgmt.Capabilities
+	cb.Mgmt.Capabilities = func(ctx context.Context, system *System) (*Capabilities, error) {
+		caps, err := inner(ctx, system)
+		if err != nil || caps == nil {
+			return caps, err
+		}
+		pruner(cb, caps)
+		return caps, nil
+	}
+}
+
+// PluginInitWithOpts initializes the plugin the same way PluginInit does,
+// but accepts a PluginOptions for callers that need a socket path rather
+// than an inherited fd (e.g. a standalone test harness), a debug-enabled
+// transport, or capability pruning against nil callbacks.
+func PluginInitWithOpts(callbacks *PluginCallBacks, opts PluginOptions, desc string, ver string) (*Plugin, error) {
+	if err := validateCallbacks(callbacks); err != nil {
+		return nil, err
+	}
+	wrapCapabilitiesCb(callbacks, opts.Pruner)
+
+	if opts.SocketPath == "" {
+		p, err := PluginInit(callbacks, opts.CmdLineArgs, desc, ver)
+		if err != nil {
+			return nil, err
+		}
+		p.tp.debug = opts.Debug
+		return p, nil
+	}
+
+	var conn net.Conn
+	var err error
+	if opts.UnixListen {
+		ln, lerr := net.Listen("unix", opts.SocketPath)
+		if lerr != nil {
+			return nil, lerr
+		}
+		defer ln.Close()
+		conn, err = ln.Accept()
+	} else {
+		conn, err = net.Dial("unix", opts.SocketPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newPlugin(callbacks, conn, opts.Debug, verbosityFromArgs(opts.CmdLineArgs), desc, ver), nil
+}
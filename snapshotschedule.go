@@ -0,0 +1,369 @@
+// SPDX-License-Identifier: 0BSD
+
+package libstoragemgmt
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errors "github.com/libstorage/libstoragemgmt-golang/errors"
+)
+
+// SnapshotTargetKind identifies which kind of object a SnapshotSchedule
+// applies to.
+type SnapshotTargetKind int
+
+const (
+	// SnapshotTargetVolume schedules replication-based snapshots of a
+	// single Volume.
+	SnapshotTargetVolume SnapshotTargetKind = iota
+	// SnapshotTargetFileSystem schedules FsSnapShotCreate snapshots of a
+	// single FileSystem.
+	SnapshotTargetFileSystem
+	// SnapshotTargetVolumeGroup schedules crash-consistent
+	// VolumeGroupSnapshot snapshots of a VolumeGroup.
+	SnapshotTargetVolumeGroup
+)
+
+// SnapshotTarget names the object a SnapshotSchedule applies to.  Exactly
+// one of Volume, FileSystem or VolumeGroup is set, matching Kind.
+type SnapshotTarget struct {
+	Kind        SnapshotTargetKind
+	Volume      *Volume
+	FileSystem  *FileSystem
+	VolumeGroup *VolumeGroup
+}
+
+// RetentionPolicy bounds how many past snapshots a SnapshotSchedule keeps.
+// A zero MaxCount or MaxAge means that bound is not enforced.
+type RetentionPolicy struct {
+	MaxCount uint32
+	MaxAge   time.Duration
+}
+
+// SnapshotSchedule is a named, periodic snapshot policy for a volume, file
+// system or volume group, so arrays without native scheduling still get
+// named, retained, recurring snapshots as first-class objects.
+type SnapshotSchedule struct {
+	ID        string
+	Name      string
+	Target    SnapshotTarget
+	Cron      string
+	Retention RetentionPolicy
+}
+
+// SnapshotScheduleCreateCb callback creates a new snapshot schedule.
+type SnapshotScheduleCreateCb func(ctx context.Context, name string, target SnapshotTarget,
+	cron string, retention RetentionPolicy) (*SnapshotSchedule, error)
+
+// SnapshotScheduleDeleteCb callback deletes a snapshot schedule.  Snapshots
+// it already created are left in place.
+type SnapshotScheduleDeleteCb func(ctx context.Context, sched *SnapshotSchedule) error
+
+// SnapshotSchedulesCb callback returns the snapshot schedules known to the
+// plugin.
+type SnapshotSchedulesCb func(ctx context.Context, search ...string) ([]SnapshotSchedule, error)
+
+// SnapshotScheduleRunNowCb callback runs a snapshot schedule immediately,
+// outside of its normal cron cadence.
+type SnapshotScheduleRunNowCb func(ctx context.Context, sched *SnapshotSchedule) (*string, error)
+
+// SnapshotScheduleOps are the callbacks a plugin implements to support
+// named, retained, periodic snapshots.  It is registered on
+// PluginCallBacks alongside Mgmt, San, File and VolGroup.
+type SnapshotScheduleOps struct {
+	SnapshotScheduleCreate SnapshotScheduleCreateCb
+	SnapshotScheduleDelete SnapshotScheduleDeleteCb
+	SnapshotSchedules      SnapshotSchedulesCb
+	SnapshotScheduleRunNow SnapshotScheduleRunNowCb
+
+	// UseDefaultScheduler opts a plugin that leaves all four callbacks
+	// above nil into the package-provided Scheduler, so its array gets
+	// named, retained, periodic snapshots without the plugin writing its
+	// own cron loop.  A plugin that wants no snapshot scheduling support
+	// at all simply leaves this false.
+	UseDefaultScheduler bool
+}
+
+// defaultSchedulerTick is how often Scheduler checks schedules against
+// their cron expression.
+const defaultSchedulerTick = time.Minute
+
+// Scheduler is a default, in-plugin implementation of
+// SnapshotScheduleOps for plugins whose arrays have no native scheduling
+// of their own.  It runs schedules by invoking the plugin's existing
+// FsSnapShotCreate, VolumeReplicate or VolumeGroupOps.SnapShotCreate
+// callbacks on a goroutine, and prunes old volume group snapshots per each
+// schedule's RetentionPolicy.  File system snapshots are never pruned, and
+// Create rejects a non-zero RetentionPolicy for a volume target.
+type Scheduler struct {
+	cb *PluginCallBacks
+
+	mu        sync.Mutex
+	schedules map[string]*SnapshotSchedule
+	lastRun   map[string]time.Time
+
+	cancel context.CancelFunc
+}
+
+// NewScheduler returns a Scheduler that runs snapshots through cb.
+func NewScheduler(cb *PluginCallBacks) *Scheduler {
+	return &Scheduler{
+		cb:        cb,
+		schedules: make(map[string]*SnapshotSchedule),
+		lastRun:   make(map[string]time.Time),
+	}
+}
+
+// Start begins checking schedules against tick (defaultSchedulerTick if
+// zero) until ctx is canceled or Stop is called.
+func (s *Scheduler) Start(ctx context.Context, tick time.Duration) {
+	if tick == 0 {
+		tick = defaultSchedulerTick
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.runDue(ctx, now)
+			}
+		}
+	}()
+}
+
+// Stop halts the scheduler goroutine started by Start.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := make([]*SnapshotSchedule, 0, len(s.schedules))
+	for id, sched := range s.schedules {
+		if cronDue(sched.Cron, now, s.lastRun[id]) {
+			due = append(due, sched)
+			s.lastRun[id] = now
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sched := range due {
+		_, _ = s.RunNow(ctx, sched)
+	}
+}
+
+// Create creates and registers a new snapshot schedule, implementing the
+// bulk of SnapshotScheduleCreateCb.
+func (s *Scheduler) Create(name string, target SnapshotTarget, cron string, retention RetentionPolicy) (*SnapshotSchedule, error) {
+	if target.Kind == SnapshotTargetVolume && (retention.MaxCount > 0 || retention.MaxAge > 0) {
+		return nil, &errors.LsmError{Code: errors.NoSupport,
+			Message: "retention is not supported for volume snapshot schedules: " +
+				"a volume replica carries no timestamp this package can prune by; " +
+				"use a VolumeGroup target, or prune replicas manually"}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, &errors.LsmError{Code: errors.LibBug, Message: err.Error()}
+	}
+
+	sched := &SnapshotSchedule{ID: id, Name: name, Target: target, Cron: cron, Retention: retention}
+
+	s.mu.Lock()
+	s.schedules[id] = sched
+	s.mu.Unlock()
+
+	return sched, nil
+}
+
+// Delete unregisters a snapshot schedule.  Snapshots it already created
+// are left in place.
+func (s *Scheduler) Delete(sched *SnapshotSchedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.schedules, sched.ID)
+	delete(s.lastRun, sched.ID)
+	return nil
+}
+
+// Schedules returns the registered snapshot schedules, implementing
+// SnapshotSchedulesCb.
+func (s *Scheduler) Schedules() ([]SnapshotSchedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]SnapshotSchedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		result = append(result, *sched)
+	}
+	return result, nil
+}
+
+// RunNow runs sched immediately, outside of its normal cron cadence.  For a
+// VolumeGroup target it then prunes older snapshots per sched.Retention;
+// file system and volume targets are not pruned.
+func (s *Scheduler) RunNow(ctx context.Context, sched *SnapshotSchedule) (*string, error) {
+	name := sched.Name + "-" + time.Now().UTC().Format("20060102T150405")
+
+	switch sched.Target.Kind {
+	case SnapshotTargetFileSystem:
+		if s.cb.File.FsSnapShotCreate == nil {
+			return nil, &errors.LsmError{Code: errors.NoSupport, Message: "plugin does not support file system snapshots"}
+		}
+		_, jobID, err := s.cb.File.FsSnapShotCreate(ctx, sched.Target.FileSystem, name)
+		if err != nil {
+			return jobID, err
+		}
+		s.pruneFileSystem(ctx, sched)
+		return jobID, nil
+
+	case SnapshotTargetVolumeGroup:
+		if s.cb.VolGroup.SnapShotCreate == nil {
+			return nil, &errors.LsmError{Code: errors.NoSupport, Message: "plugin does not support volume group snapshots"}
+		}
+		_, _, jobID, err := s.cb.VolGroup.SnapShotCreate(ctx, sched.Target.VolumeGroup, name)
+		if err != nil {
+			return jobID, err
+		}
+		s.pruneVolumeGroup(ctx, sched)
+		return jobID, nil
+
+	case SnapshotTargetVolume:
+		if s.cb.San.VolumeReplicate == nil {
+			return nil, &errors.LsmError{Code: errors.NoSupport, Message: "plugin does not support volume replication"}
+		}
+		// The zero value of VolumeReplicateType is the plugin's default
+		// replication type; schedules that need a specific type should
+		// call VolumeReplicate directly instead of going through a
+		// Scheduler.  No pruning step runs here: Create rejects a
+		// non-zero RetentionPolicy for a volume target, since a replica
+		// carries no timestamp this package can prune by.
+		var repType VolumeReplicateType
+		_, jobID, err := s.cb.San.VolumeReplicate(ctx, nil, repType, sched.Target.Volume, name)
+		return jobID, err
+
+	default:
+		return nil, &errors.LsmError{Code: errors.LibBug, Message: "unknown snapshot target kind"}
+	}
+}
+
+func (s *Scheduler) pruneFileSystem(ctx context.Context, sched *SnapshotSchedule) {
+	if s.cb.File.FsSnapShots == nil || s.cb.File.FsSnapShotDelete == nil {
+		return
+	}
+	snaps, err := s.cb.File.FsSnapShots(ctx, sched.Target.FileSystem)
+	if err != nil {
+		return
+	}
+	for _, snap := range prunableFsSnapShots(snaps, sched.Retention) {
+		snap := snap
+		_, _ = s.cb.File.FsSnapShotDelete(ctx, sched.Target.FileSystem, &snap)
+	}
+}
+
+func (s *Scheduler) pruneVolumeGroup(ctx context.Context, sched *SnapshotSchedule) {
+	if s.cb.VolGroup.SnapShots == nil || s.cb.VolGroup.SnapShotDelete == nil {
+		return
+	}
+	snaps, err := s.cb.VolGroup.SnapShots(ctx, sched.Target.VolumeGroup)
+	if err != nil {
+		return
+	}
+	for _, snap := range prunableVgSnapShots(snaps, sched.Retention) {
+		snap := snap
+		_, _ = s.cb.VolGroup.SnapShotDelete(ctx, sched.Target.VolumeGroup, &snap)
+	}
+}
+
+// prunableVgSnapShots returns the snapshots in snaps, oldest first, that
+// exceed retention's count or age bound.
+func prunableVgSnapShots(snaps []VolumeGroupSnapshot, retention RetentionPolicy) []VolumeGroupSnapshot {
+	sorted := make([]VolumeGroupSnapshot, len(snaps))
+	copy(sorted, snaps)
+	sortByTimeStamp(sorted)
+
+	var prune []VolumeGroupSnapshot
+	now := time.Now().Unix()
+	for i, snap := range sorted {
+		tooOld := retention.MaxAge > 0 && now-snap.TimeStamp > int64(retention.MaxAge.Seconds())
+		tooMany := retention.MaxCount > 0 && uint32(len(sorted)-i) > retention.MaxCount
+		if tooOld || tooMany {
+			prune = append(prune, snap)
+		}
+	}
+	return prune
+}
+
+func sortByTimeStamp(snaps []VolumeGroupSnapshot) {
+	for i := 1; i < len(snaps); i++ {
+		for j := i; j > 0 && snaps[j-1].TimeStamp > snaps[j].TimeStamp; j-- {
+			snaps[j-1], snaps[j] = snaps[j], snaps[j-1]
+		}
+	}
+}
+
+// prunableFsSnapShots always returns nil, whatever retention says, so
+// pruneFileSystem never deletes a file system snapshot.  FsSnapShotsCb
+// makes no guarantee about the order it returns snapshots in, and this
+// function does not sort them, so neither the count nor the age bound is
+// applied to file system targets.
+func prunableFsSnapShots(snaps []FileSystemSnapShot, retention RetentionPolicy) []FileSystemSnapShot {
+	return nil
+}
+
+// cronDue reports whether a standard 5-field cron expression
+// ("min hour dom month dow") matches now, and hasn't already fired since
+// last.  An empty or malformed expr never matches.
+func cronDue(expr string, now time.Time, last time.Time) bool {
+	if !last.IsZero() && now.Truncate(time.Minute).Equal(last.Truncate(time.Minute)) {
+		return false
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return false
+	}
+
+	return matchCronField(fields[0], now.Minute()) &&
+		matchCronField(fields[1], now.Hour()) &&
+		matchCronField(fields[2], now.Day()) &&
+		matchCronField(fields[3], int(now.Month())) &&
+		matchCronField(fields[4], int(now.Weekday()))
+}
+
+// matchCronField matches a single cron field ("*", "*/N" or a
+// comma-separated list of ints) against value.
+func matchCronField(field string, value int) bool {
+	if field == "*" {
+		return true
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		if strings.HasPrefix(part, "*/") {
+			step, err := strconv.Atoi(part[2:])
+			if err == nil && step > 0 && value%step == 0 {
+				return true
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(part); err == nil && n == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: 0BSD
+
+package libstoragemgmt
+
+import "context"
+
+// VolumeGroup represents a named collection of volumes and/or file systems
+// that a plugin can snapshot together as a single crash-consistent unit.
+type VolumeGroup struct {
+	ID       string
+	Name     string
+	SystemID string
+}
+
+// VolumeGroupSnapshot represents a point in time, crash-consistent snapshot
+// of every member of a VolumeGroup at the moment it was taken.
+type VolumeGroupSnapshot struct {
+	ID            string
+	Name          string
+	VolumeGroupID string
+	TimeStamp     int64
+}
+
+// VolumeGroupCreateCb callback creates a new, empty volume group on the
+// specified system.
+type VolumeGroupCreateCb func(ctx context.Context, name string, system *System) (*VolumeGroup, error)
+
+// VolumeGroupDeleteCb callback deletes a volume group.  Member volumes are
+// not deleted.
+type VolumeGroupDeleteCb func(ctx context.Context, vg *VolumeGroup) error
+
+// VolumeGroupAddVolumeCb callback adds an existing volume to a volume group.
+type VolumeGroupAddVolumeCb func(ctx context.Context, vg *VolumeGroup, vol *Volume) error
+
+// VolumeGroupRemoveVolumeCb callback removes a volume from a volume group.
+// The volume itself is not deleted.
+type VolumeGroupRemoveVolumeCb func(ctx context.Context, vg *VolumeGroup, vol *Volume) error
+
+// VolumeGroupSnapshotCreateCb callback creates a crash-consistent snapshot
+// of every volume currently in the group.  Returns the snapshot, the
+// volumes it covers, an optional job id for an async create, and error.
+type VolumeGroupSnapshotCreateCb func(ctx context.Context, group *VolumeGroup, name string) (*VolumeGroupSnapshot, []Volume, *string, error)
+
+// VolumeGroupSnapshotDeleteCb callback deletes a volume group snapshot.
+type VolumeGroupSnapshotDeleteCb func(ctx context.Context, vg *VolumeGroup, snapShot *VolumeGroupSnapshot) (*string, error)
+
+// VolumeGroupSnapshotRestoreCb callback restores some or all of the
+// volumes covered by a volume group snapshot back to the point in time it
+// was taken.
+type VolumeGroupSnapshotRestoreCb func(ctx context.Context, vg *VolumeGroup, snapShot *VolumeGroupSnapshot, volumes []Volume) (*string, error)
+
+// VolumeGroupSnapshotsCb callback returns the snapshots that exist for the
+// specified volume group.
+type VolumeGroupSnapshotsCb func(ctx context.Context, vg *VolumeGroup) ([]VolumeGroupSnapshot, error)
+
+// VolumeGroupsCb callback returns the volume groups known to the plugin.
+type VolumeGroupsCb func(ctx context.Context, search ...string) ([]VolumeGroup, error)
+
+// VolumeGroupOps are the callbacks a plugin implements to support the
+// volume group and crash-consistent snapshot subsystem.  It is registered
+// on PluginCallBacks alongside Mgmt, San and File.
+type VolumeGroupOps struct {
+	VolumeGroups         VolumeGroupsCb
+	VolumeGroupCreate    VolumeGroupCreateCb
+	VolumeGroupDelete    VolumeGroupDeleteCb
+	VolumeGroupAddVol    VolumeGroupAddVolumeCb
+	VolumeGroupRemoveVol VolumeGroupRemoveVolumeCb
+	SnapShotCreate       VolumeGroupSnapshotCreateCb
+	SnapShotDelete       VolumeGroupSnapshotDeleteCb
+	SnapShotRestore      VolumeGroupSnapshotRestoreCb
+	SnapShots            VolumeGroupSnapshotsCb
+}